@@ -0,0 +1,56 @@
+// Package ruebucket is a distributed rate limiter backed by Redis (via
+// rueidis), with optional local caching so a host can avoid waiting on a
+// round trip for decisions it already has high confidence in: gcra's deny
+// cache for keys it just denied, and TokenBucket's HotKeyFilter for keys
+// it has never seen (the spend still goes to Redis in the background;
+// single-process deployments only; see HotKeyFilter's doc).
+package ruebucket
+
+import (
+	"context"
+	"time"
+)
+
+// Source indicates which layer produced a Result, for callers (e.g. the
+// otel subpackage) that want to report it.
+type Source string
+
+const (
+	// SourceRedis means the decision came from a round trip to Redis.
+	SourceRedis Source = "redis"
+	// SourceTTLCache means a local cache answered without waiting for a
+	// Redis round trip, e.g. HotKeyFilter recognizing a key it has never
+	// seen (its spend is then sent to Redis in the background).
+	SourceTTLCache Source = "ttlcache"
+	// SourceFallback means Redis was unreachable and the limiter fell
+	// back to a local-only decision.
+	SourceFallback Source = "fallback"
+)
+
+// Result describes the outcome of a single Allow call.
+type Result struct {
+	// Allowed reports whether the request was permitted.
+	Allowed bool
+	// Remaining is the number of additional requests the caller could make
+	// right now without being denied.
+	Remaining int64
+	// RetryAfter is how long the caller should wait before retrying a
+	// denied request. Zero when Allowed is true.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket returns to full capacity.
+	ResetAfter time.Duration
+	// Source is which layer produced this Result. Zero value is treated
+	// as SourceRedis by callers that care.
+	Source Source
+}
+
+// Bucket is a keyed rate limiter: each key (e.g. a client IP or API token)
+// is tracked independently. Implementations in this module family include
+// TokenBucket and the GCRA limiter in the gcra subpackage, and both can be
+// wrapped by the otel and sharded packages since they all satisfy this
+// interface.
+type Bucket interface {
+	// Allow consumes one unit of capacity for key, returning whether the
+	// request is allowed and the resulting bucket state.
+	Allow(ctx context.Context, key string) (Result, error)
+}
@@ -0,0 +1,159 @@
+// Package gcra implements the Generic Cell Rate Algorithm as an
+// alternative to the token bucket in the root ruebucket package. A single
+// Redis key holds the theoretical arrival time (TAT) for the next
+// request; each Allow call advances it by one emission interval and
+// denies the request if doing so would exceed the configured burst
+// tolerance. This avoids the boundary-burst problem of fixed windows
+// without the cost of a sliding log.
+package gcra
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/redis/rueidis"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+//go:embed gcra.lua
+var script string
+
+// Limiter is a Redis-backed GCRA rate limiter allowing `limit` requests
+// per `period`, with bursts of up to `burst` requests tolerated beyond
+// the steady-state rate.
+type Limiter struct {
+	client rueidis.Client
+	script *rueidis.Lua
+
+	burst            int64
+	emissionInterval time.Duration
+	dvt              time.Duration
+
+	prefix    string
+	denyCache *ttlcache.Cache[string, time.Time]
+}
+
+var _ ruebucket.Bucket = (*Limiter)(nil)
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithKeyPrefix namespaces every Redis key written by this limiter.
+func WithKeyPrefix(prefix string) Option {
+	return func(l *Limiter) { l.prefix = prefix }
+}
+
+// WithLocalDenyCache short-circuits obviously-denied keys: once a key is
+// denied, the local process remembers the time it will next become
+// eligible and returns a denial directly, without a Redis round trip,
+// until that time passes. It has no effect on allowed requests, since the
+// TAT advances on every successful call and can't be served from a stale
+// local copy.
+func WithLocalDenyCache() Option {
+	return func(l *Limiter) {
+		l.denyCache = ttlcache.New[string, time.Time]()
+		go l.denyCache.Start()
+	}
+}
+
+// maxInterval stands in for "infinitely far apart" when limit <= 0. New
+// pairs it with a zero delay variation tolerance, so every request's
+// allow time lies one maxInterval in the future and it is denied.
+const maxInterval = time.Duration(1) << 32
+
+// New returns a Limiter that allows `limit` requests per `period` on
+// average, tolerating bursts of up to `burst` requests above that rate.
+// A misconfigured limit <= 0 degrades to a limiter that denies every
+// request rather than panicking on an integer divide-by-zero, mirroring
+// how TokenBucket's New degrades a zero rate instead of crashing.
+func New(client rueidis.Client, limit int64, period time.Duration, burst int64, opts ...Option) *Limiter {
+	l := &Limiter{
+		client: client,
+		script: rueidis.NewLuaScript(script),
+		burst:  burst,
+	}
+	if limit > 0 {
+		l.emissionInterval = period / time.Duration(limit)
+		l.dvt = time.Duration(burst) * l.emissionInterval
+	} else {
+		l.emissionInterval = maxInterval
+		l.dvt = 0
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Allow consumes one unit of capacity for key.
+func (l *Limiter) Allow(ctx context.Context, key string) (ruebucket.Result, error) {
+	now := time.Now()
+
+	if l.denyCache != nil {
+		if item := l.denyCache.Get(key); item != nil {
+			if allowAt := item.Value(); now.Before(allowAt) {
+				wait := allowAt.Sub(now)
+				// allowAt is tat + emissionInterval - dvt, so the stored TAT
+				// (full capacity) is this far away.
+				reset := max(wait+l.dvt-l.emissionInterval, 0)
+				return ruebucket.Result{Allowed: false, RetryAfter: wait, ResetAfter: reset, Source: ruebucket.SourceTTLCache}, nil
+			}
+		}
+	}
+
+	resp := l.script.Exec(ctx, l.client,
+		[]string{l.prefix + key},
+		[]string{
+			fmt.Sprintf("%d", l.emissionInterval.Milliseconds()),
+			fmt.Sprintf("%d", l.dvt.Milliseconds()),
+			fmt.Sprintf("%d", now.UnixMilli()),
+			fmt.Sprintf("%d", l.burst),
+		},
+	)
+	arr, err := resp.ToArray()
+	if err != nil {
+		return ruebucket.Result{}, fmt.Errorf("gcra: script: %w", err)
+	}
+	allowed, err := arr[0].ToInt64()
+	if err != nil {
+		return ruebucket.Result{}, fmt.Errorf("gcra: parse allowed: %w", err)
+	}
+	remaining, err := arr[1].ToInt64()
+	if err != nil {
+		return ruebucket.Result{}, fmt.Errorf("gcra: parse remaining: %w", err)
+	}
+	retryAfterMs, err := arr[2].ToInt64()
+	if err != nil {
+		return ruebucket.Result{}, fmt.Errorf("gcra: parse retry_after: %w", err)
+	}
+	resetAfterMs, err := arr[3].ToInt64()
+	if err != nil {
+		return ruebucket.Result{}, fmt.Errorf("gcra: parse reset_after: %w", err)
+	}
+
+	result := ruebucket.Result{
+		Allowed:    allowed == 1,
+		Remaining:  remaining,
+		RetryAfter: time.Duration(retryAfterMs) * time.Millisecond,
+		ResetAfter: time.Duration(resetAfterMs) * time.Millisecond,
+		Source:     ruebucket.SourceRedis,
+	}
+
+	if !result.Allowed && l.denyCache != nil {
+		l.denyCache.Set(key, now.Add(result.RetryAfter), result.RetryAfter)
+	}
+
+	return result, nil
+}
+
+// Close stops the local deny cache's background cleanup goroutine, if one
+// was started via WithLocalDenyCache. It is a no-op otherwise.
+func (l *Limiter) Close() {
+	if l.denyCache != nil {
+		l.denyCache.Stop()
+	}
+}
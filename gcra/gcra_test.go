@@ -0,0 +1,39 @@
+package gcra
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewComputesEmissionIntervalAndTolerance(t *testing.T) {
+	l := New(nil, 10, time.Second, 5)
+
+	require.Equal(t, 100*time.Millisecond, l.emissionInterval)
+	require.Equal(t, 500*time.Millisecond, l.dvt)
+	require.Nil(t, l.denyCache)
+}
+
+func TestNewWithZeroLimitDoesNotPanic(t *testing.T) {
+	require.NotPanics(t, func() {
+		l := New(nil, 0, time.Second, 5)
+		require.Equal(t, maxInterval, l.emissionInterval)
+		require.Equal(t, time.Duration(0), l.dvt)
+	})
+}
+
+func TestWithLocalDenyCacheShortCircuitsWithoutRedis(t *testing.T) {
+	l := New(nil, 10, time.Second, 5, WithLocalDenyCache())
+	defer l.Close()
+
+	now := time.Now()
+	l.denyCache.Set("k", now.Add(50*time.Millisecond), 50*time.Millisecond)
+
+	result, err := l.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Greater(t, result.RetryAfter, time.Duration(0))
+	require.LessOrEqual(t, result.RetryAfter, 50*time.Millisecond)
+}
@@ -0,0 +1,118 @@
+package ruebucket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bits-and-blooms/bloom/v3"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// HotKeyFilter lets a TokenBucket answer without waiting for Redis for a
+// key it has never seen before in this process's lifetime: such a key is
+// trivially at full capacity, so there's nothing Redis can tell us that
+// we don't already know. The spend is still sent to Redis in the
+// background, so Redis sees the same number of calls; what is saved is
+// the caller's wait. This matters for limiters facing a long tail of
+// rarely-seen keys, e.g. per-IP limits under scanning traffic.
+//
+// Membership is tracked with two rotating Bloom filters (active + aging)
+// so the false-positive rate doesn't climb unboundedly over a long
+// process lifetime: every RotationInterval the aging filter is dropped
+// and replaced by the active one, and a key is considered seen if either
+// reports it, so an observed key stays in the filters for between one and
+// two RotationIntervals. A ttlcache additionally remembers each observed
+// key for rotationBridgeWindow. None of this needs to be exact: a false
+// positive (reporting a key as seen when it hasn't been) only makes the
+// caller wait for a Redis round trip it could have skipped waiting for.
+//
+// A false negative is a different matter: it grants a free, unverified
+// allow. Membership here is purely local to one process, so it is only
+// safe to use when exactly one process enforces a given bucket. With
+// multiple replicas sharing a bucket behind a load balancer, each
+// replica's filters start out empty, so the first request that happens
+// to land on each replica is reported as unseen there and gets a free
+// full-capacity allow regardless of the key's actual state in Redis,
+// independently, once per replica. Don't use WithHotKeyFilter in a
+// horizontally-scaled deployment unless every key is pinned to a single
+// replica some other way (e.g. consistent hashing upstream of this
+// process).
+type HotKeyFilter struct {
+	capacity uint
+	fpRate   float64
+
+	mu     sync.RWMutex
+	active *bloom.BloomFilter
+	aging  *bloom.BloomFilter
+	recent *ttlcache.Cache[string, struct{}]
+
+	stop chan struct{}
+}
+
+// rotationBridgeWindow is how long the ttlcache remembers an observed key
+// on its own, independent of rotationInterval. A fixed short window keeps
+// memory use flat regardless of how rotationInterval is configured.
+const rotationBridgeWindow = 5 * time.Second
+
+// NewHotKeyFilter returns a HotKeyFilter sized for roughly capacity
+// distinct keys per rotation window at the given false-positive rate,
+// rotating its filters every rotationInterval.
+func NewHotKeyFilter(capacity uint, fpRate float64, rotationInterval time.Duration) *HotKeyFilter {
+	f := &HotKeyFilter{
+		capacity: capacity,
+		fpRate:   fpRate,
+		active:   bloom.NewWithEstimates(capacity, fpRate),
+		aging:    bloom.NewWithEstimates(capacity, fpRate),
+		recent:   ttlcache.New[string, struct{}](ttlcache.WithTTL[string, struct{}](rotationBridgeWindow)),
+		stop:     make(chan struct{}),
+	}
+	go f.recent.Start()
+	go f.rotateLoop(rotationInterval)
+	return f
+}
+
+func (f *HotKeyFilter) rotateLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.rotate()
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+func (f *HotKeyFilter) rotate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.aging = f.active
+	f.active = bloom.NewWithEstimates(f.capacity, f.fpRate)
+}
+
+// seen reports whether key has been observed since the last rotation that
+// dropped it.
+func (f *HotKeyFilter) seen(key string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.recent.Has(key) {
+		return true
+	}
+	return f.active.TestString(key) || f.aging.TestString(key)
+}
+
+// observe marks key as seen.
+func (f *HotKeyFilter) observe(key string) {
+	f.mu.Lock()
+	f.active.AddString(key)
+	f.mu.Unlock()
+	f.recent.Set(key, struct{}{}, ttlcache.DefaultTTL)
+}
+
+// Close stops the filter's background rotation and cache-eviction
+// goroutines.
+func (f *HotKeyFilter) Close() {
+	close(f.stop)
+	f.recent.Stop()
+}
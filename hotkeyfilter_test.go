@@ -0,0 +1,29 @@
+package ruebucket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotKeyFilterSeenAfterObserve(t *testing.T) {
+	f := NewHotKeyFilter(1000, 0.01, time.Hour)
+	defer f.Close()
+
+	require.False(t, f.seen("a"))
+	f.observe("a")
+	require.True(t, f.seen("a"))
+	require.False(t, f.seen("b"))
+}
+
+func TestHotKeyFilterRotateMovesActiveIntoAging(t *testing.T) {
+	f := NewHotKeyFilter(1000, 0.01, time.Hour)
+	defer f.Close()
+
+	f.observe("a")
+	f.rotate()
+
+	require.True(t, f.aging.TestString("a"))
+	require.False(t, f.active.TestString("a"))
+}
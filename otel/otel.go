@@ -0,0 +1,132 @@
+// Package otel wraps a ruebucket.Bucket with OpenTelemetry tracing and
+// metrics, so rate-limit decisions are debuggable per request and
+// tunable in aggregate. It lives in its own module so that using it is
+// opt-in: pulling in the OpenTelemetry SDK isn't forced on every
+// ruebucket user.
+package otel
+
+import (
+	"context"
+	"time"
+
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+const instrumentationName = "github.com/iamcalledrob/ruebucket/otel"
+
+// Bucket wraps a ruebucket.Bucket, adding a span and a set of metrics
+// around every Allow call.
+type Bucket struct {
+	next     ruebucket.Bucket
+	capacity int64
+
+	tracer trace.Tracer
+
+	decisions   metric.Int64Counter
+	latency     metric.Float64Histogram
+	cacheResult metric.Int64Counter
+}
+
+var _ ruebucket.Bucket = (*Bucket)(nil)
+
+// Option configures a Bucket.
+type Option func(*Bucket)
+
+// WithTracerProvider overrides the TracerProvider used to create spans.
+// Defaults to the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(b *Bucket) { b.tracer = tp.Tracer(instrumentationName) }
+}
+
+// WithMeterProvider overrides the MeterProvider used to create
+// instruments. Defaults to the global provider.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(b *Bucket) { b.registerInstruments(mp) }
+}
+
+// New wraps next, reporting capacity as a span attribute on every call
+// so dashboards can be sliced by configured limit as well as by key.
+func New(next ruebucket.Bucket, capacity int64, opts ...Option) *Bucket {
+	b := &Bucket{next: next, capacity: capacity}
+	b.tracer = otelapi.Tracer(instrumentationName)
+	b.registerInstruments(otelapi.GetMeterProvider())
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *Bucket) registerInstruments(mp metric.MeterProvider) {
+	meter := mp.Meter(instrumentationName)
+	// Errors here just mean nil instruments, which the no-op guards below
+	// already handle; an instrumentation library shouldn't fail the
+	// caller's rate limiting because a metric couldn't be registered.
+	b.decisions, _ = meter.Int64Counter("ruebucket.decisions",
+		metric.WithDescription("Count of Allow decisions by outcome"))
+	b.latency, _ = meter.Float64Histogram("ruebucket.latency",
+		metric.WithDescription("Allow call latency"), metric.WithUnit("ms"))
+	b.cacheResult, _ = meter.Int64Counter("ruebucket.local_cache",
+		metric.WithDescription("Count of local ttlcache hits vs misses"))
+}
+
+// Allow calls the wrapped Bucket, recording a span with bucket.key,
+// bucket.capacity, bucket.allowed, bucket.remaining and bucket.source
+// attributes, plus latency and allow/deny counter metrics.
+func (b *Bucket) Allow(ctx context.Context, key string) (ruebucket.Result, error) {
+	ctx, span := b.tracer.Start(ctx, "ruebucket.Allow")
+	defer span.End()
+
+	start := time.Now()
+	result, err := b.next.Allow(ctx, key)
+	elapsed := time.Since(start)
+
+	source := result.Source
+	if source == "" {
+		source = ruebucket.SourceRedis
+	}
+
+	// Tag every latency sample with its source so an operator filtering
+	// this histogram to source=redis sees real round-trip latency,
+	// undiluted by near-zero ttlcache/fallback hits answered locally.
+	if b.latency != nil {
+		b.latency.Record(ctx, float64(elapsed.Microseconds())/1000.0,
+			metric.WithAttributes(attribute.String("bucket.source", string(source))))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return result, err
+	}
+
+	span.SetAttributes(
+		attribute.String("bucket.key", key),
+		attribute.Int64("bucket.capacity", b.capacity),
+		attribute.Bool("bucket.allowed", result.Allowed),
+		attribute.Int64("bucket.remaining", result.Remaining),
+		attribute.String("bucket.source", string(source)),
+	)
+
+	if b.decisions != nil {
+		outcome := "deny"
+		if result.Allowed {
+			outcome = "allow"
+		}
+		b.decisions.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+	}
+	if b.cacheResult != nil {
+		hit := "miss"
+		if source == ruebucket.SourceTTLCache {
+			hit = "hit"
+		}
+		b.cacheResult.Add(ctx, 1, metric.WithAttributes(attribute.String("result", hit)))
+	}
+
+	return result, nil
+}
@@ -0,0 +1,36 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+type fakeBucket struct {
+	result ruebucket.Result
+	err    error
+}
+
+func (f *fakeBucket) Allow(ctx context.Context, key string) (ruebucket.Result, error) {
+	return f.result, f.err
+}
+
+func TestAllowPassesThroughResultUsingNoopProviders(t *testing.T) {
+	next := &fakeBucket{result: ruebucket.Result{Allowed: true, Remaining: 3, Source: ruebucket.SourceTTLCache}}
+	b := New(next, 10)
+
+	result, err := b.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	require.Equal(t, next.result, result)
+}
+
+func TestAllowPropagatesError(t *testing.T) {
+	next := &fakeBucket{err: context.DeadlineExceeded}
+	b := New(next, 10)
+
+	_, err := b.Allow(context.Background(), "k")
+	require.Error(t, err)
+}
@@ -0,0 +1,70 @@
+// Package ruebucketgrpc adapts a ruebucket.Bucket into gRPC server
+// interceptors, returning codes.ResourceExhausted with a
+// google.rpc.RetryInfo detail on denial instead of making every service
+// reimplement that convention.
+package ruebucketgrpc
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+// UnaryServerInterceptor applies bucket to every unary call, keyed by
+// keyFunc.
+func UnaryServerInterceptor(bucket ruebucket.Bucket, keyFunc KeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key, err := keyFunc(ctx, info.FullMethod)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "ruebucketgrpc: rate limit key: %v", err)
+		}
+
+		result, err := bucket.Allow(ctx, key)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "ruebucketgrpc: rate limiter unavailable: %v", err)
+		}
+		if !result.Allowed {
+			return nil, deniedStatus(result).Err()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor applies bucket to every streaming call, keyed
+// by keyFunc and checked once before the handler starts streaming.
+func StreamServerInterceptor(bucket ruebucket.Bucket, keyFunc KeyFunc) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		key, err := keyFunc(ss.Context(), info.FullMethod)
+		if err != nil {
+			return status.Errorf(codes.Internal, "ruebucketgrpc: rate limit key: %v", err)
+		}
+
+		result, err := bucket.Allow(ss.Context(), key)
+		if err != nil {
+			return status.Errorf(codes.Internal, "ruebucketgrpc: rate limiter unavailable: %v", err)
+		}
+		if !result.Allowed {
+			return deniedStatus(result).Err()
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func deniedStatus(result ruebucket.Result) *status.Status {
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	withDetails, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(result.RetryAfter),
+	})
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
@@ -0,0 +1,150 @@
+package ruebucketgrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+type stubBucket struct {
+	result ruebucket.Result
+	err    error
+}
+
+func (s *stubBucket) Allow(ctx context.Context, key string) (ruebucket.Result, error) {
+	return s.result, s.err
+}
+
+func okKeyFunc(ctx context.Context, fullMethod string) (string, error) { return fullMethod, nil }
+
+func failingKeyFunc(ctx context.Context, fullMethod string) (string, error) {
+	return "", errors.New("no peer")
+}
+
+// stubServerStream is a minimal grpc.ServerStream that only needs to
+// support Context(), since that's all StreamServerInterceptor uses.
+type stubServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *stubServerStream) Context() context.Context { return s.ctx }
+
+func TestUnaryServerInterceptorCallsHandlerWhenAllowed(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: true}}
+	interceptor := UnaryServerInterceptor(bucket, okKeyFunc)
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return "ok", nil
+	}
+
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, handler)
+	require.NoError(t, err)
+	require.True(t, called)
+	require.Equal(t, "ok", resp)
+}
+
+func TestUnaryServerInterceptorDeniesWithoutCallingHandler(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: false, RetryAfter: time.Second}}
+	interceptor := UnaryServerInterceptor(bucket, okKeyFunc)
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, handler)
+	require.False(t, called)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestUnaryServerInterceptorMapsKeyFuncErrorToInternal(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: true}}
+	interceptor := UnaryServerInterceptor(bucket, failingKeyFunc)
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, handler)
+	require.False(t, called)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestUnaryServerInterceptorMapsBucketErrorToInternal(t *testing.T) {
+	bucket := &stubBucket{err: errors.New("redis down")}
+	interceptor := UnaryServerInterceptor(bucket, okKeyFunc)
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, nil)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestStreamServerInterceptorCallsHandlerWhenAllowed(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: true}}
+	interceptor := StreamServerInterceptor(bucket, okKeyFunc)
+
+	called := false
+	handler := func(srv any, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+
+	err := interceptor(nil, &stubServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/svc/Method"}, handler)
+	require.NoError(t, err)
+	require.True(t, called)
+}
+
+func TestStreamServerInterceptorDeniesWithoutCallingHandler(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: false, RetryAfter: time.Second}}
+	interceptor := StreamServerInterceptor(bucket, okKeyFunc)
+
+	called := false
+	handler := func(srv any, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+
+	err := interceptor(nil, &stubServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/svc/Method"}, handler)
+	require.False(t, called)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestStreamServerInterceptorMapsKeyFuncErrorToInternal(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: true}}
+	interceptor := StreamServerInterceptor(bucket, failingKeyFunc)
+
+	called := false
+	handler := func(srv any, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+
+	err := interceptor(nil, &stubServerStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/svc/Method"}, handler)
+	require.False(t, called)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestDeniedStatusIncludesRetryInfo(t *testing.T) {
+	st := deniedStatus(ruebucket.Result{RetryAfter: 2 * time.Second})
+
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+
+	retryInfo, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Equal(t, 2*time.Second, retryInfo.RetryDelay.AsDuration())
+}
@@ -0,0 +1,54 @@
+package ruebucketgrpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc/peer"
+)
+
+// KeyFunc extracts a rate-limit key from an incoming call's context and
+// full method name (e.g. "/pkg.Service/Method").
+type KeyFunc func(ctx context.Context, fullMethod string) (string, error)
+
+// PeerKeyFunc uses the calling peer's address, the gRPC equivalent of
+// keying an HTTP limiter off the remote address.
+func PeerKeyFunc(ctx context.Context, fullMethod string) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", fmt.Errorf("ruebucketgrpc: no peer in context")
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String(), nil
+	}
+	return host, nil
+}
+
+// MethodKeyFunc limits per RPC method rather than per caller, ignoring
+// the identity of the caller entirely.
+func MethodKeyFunc(ctx context.Context, fullMethod string) (string, error) {
+	return fullMethod, nil
+}
+
+// ComposeKeyFunc joins the results of multiple KeyFuncs with sep, e.g.
+// combining the method name with the calling peer so each method gets
+// its own independent quota per caller.
+func ComposeKeyFunc(sep string, funcs ...KeyFunc) KeyFunc {
+	return func(ctx context.Context, fullMethod string) (string, error) {
+		parts := make([]string, len(funcs))
+		for i, f := range funcs {
+			part, err := f(ctx, fullMethod)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		key := parts[0]
+		for _, p := range parts[1:] {
+			key += sep + p
+		}
+		return key, nil
+	}
+}
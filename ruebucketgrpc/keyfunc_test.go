@@ -0,0 +1,23 @@
+package ruebucketgrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeKeyFuncJoinsParts(t *testing.T) {
+	a := func(ctx context.Context, fullMethod string) (string, error) { return "a", nil }
+	b := func(ctx context.Context, fullMethod string) (string, error) { return fullMethod, nil }
+
+	key, err := ComposeKeyFunc(":", a, b)(context.Background(), "/svc/Method")
+	require.NoError(t, err)
+	require.Equal(t, "a:/svc/Method", key)
+}
+
+func TestMethodKeyFuncReturnsFullMethod(t *testing.T) {
+	key, err := MethodKeyFunc(context.Background(), "/svc/Method")
+	require.NoError(t, err)
+	require.Equal(t, "/svc/Method", key)
+}
@@ -0,0 +1,67 @@
+package ruebuckethttp
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// KeyFunc extracts a rate-limit key from an incoming request.
+type KeyFunc func(r *http.Request) (string, error)
+
+// RemoteAddrKeyFunc uses the request's direct TCP peer address as the
+// key. It's safe to use with no proxy in front of the server, since
+// unlike X-Forwarded-For it can't be spoofed by the client.
+func RemoteAddrKeyFunc(r *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr, nil
+	}
+	return host, nil
+}
+
+// ForwardedForKeyFunc returns the left-most address in X-Forwarded-For,
+// falling back to RemoteAddrKeyFunc when the header is absent. Only use
+// this behind a trusted proxy that sets the header itself, replacing
+// whatever the client sent: behind a proxy that appends to the header,
+// the left-most entry is still the value the client supplied.
+func ForwardedForKeyFunc(r *http.Request) (string, error) {
+	xff := r.Header.Get("X-Forwarded-For")
+	if xff == "" {
+		return RemoteAddrKeyFunc(r)
+	}
+	first, _, _ := strings.Cut(xff, ",")
+	return strings.TrimSpace(first), nil
+}
+
+// ContextKeyFunc builds a KeyFunc that reads an already-authenticated
+// subject (or any other per-request identifier) off the request context,
+// e.g. one set by an auth middleware that runs upstream of this one.
+func ContextKeyFunc(ctxKey any) KeyFunc {
+	return func(r *http.Request) (string, error) {
+		v := r.Context().Value(ctxKey)
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("ruebuckethttp: no string value for context key %v", ctxKey)
+		}
+		return s, nil
+	}
+}
+
+// ComposeKeyFunc joins the results of multiple KeyFuncs with sep, e.g.
+// combining a route name with a client IP so each route gets its own
+// independent quota per client.
+func ComposeKeyFunc(sep string, funcs ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, error) {
+		parts := make([]string, len(funcs))
+		for i, f := range funcs {
+			part, err := f(r)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		return strings.Join(parts, sep), nil
+	}
+}
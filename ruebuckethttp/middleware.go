@@ -0,0 +1,56 @@
+// Package ruebuckethttp adapts a ruebucket.Bucket into standard
+// net/http middleware, handling RFC 6585 429 responses and the
+// draft-ietf-httpapi-ratelimit-headers RateLimit-* headers so callers
+// don't have to reimplement that wire format themselves.
+package ruebuckethttp
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+// Middleware is a func(http.Handler) http.Handler, matching the shape
+// expected by most Go HTTP routers and middleware chains.
+type Middleware func(http.Handler) http.Handler
+
+// New returns Middleware that applies bucket to every request, keyed by
+// keyFunc. capacity is reported in the RateLimit-Limit header. Denied
+// requests get a 429 with Retry-After and RateLimit-* headers instead of
+// reaching next; failures to compute a key or reach the bucket's backend
+// return a 500, since neither is something the caller can retry their way
+// out of.
+func New(bucket ruebucket.Bucket, capacity int64, keyFunc KeyFunc) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, err := keyFunc(r)
+			if err != nil {
+				http.Error(w, "ruebuckethttp: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			result, err := bucket.Allow(r.Context(), key)
+			if err != nil {
+				http.Error(w, "ruebuckethttp: rate limiter unavailable", http.StatusInternalServerError)
+				return
+			}
+
+			setRateLimitHeaders(w.Header(), capacity, result)
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func setRateLimitHeaders(h http.Header, capacity int64, result ruebucket.Result) {
+	h.Set("RateLimit-Limit", strconv.FormatInt(capacity, 10))
+	h.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	h.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.ResetAfter.Seconds()))))
+}
@@ -0,0 +1,58 @@
+package ruebuckethttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+type stubBucket struct {
+	result ruebucket.Result
+	err    error
+}
+
+func (s *stubBucket) Allow(ctx context.Context, key string) (ruebucket.Result, error) {
+	return s.result, s.err
+}
+
+func TestMiddlewareAllowsAndSetsHeaders(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: true, Remaining: 4, ResetAfter: 2 * time.Second}}
+	mw := New(bucket, 10, RemoteAddrKeyFunc)
+
+	called := false
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.1:5555"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.True(t, called)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "10", rec.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "4", rec.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "2", rec.Header().Get("RateLimit-Reset"))
+}
+
+func TestMiddlewareDeniesWith429(t *testing.T) {
+	bucket := &stubBucket{result: ruebucket.Result{Allowed: false, RetryAfter: 3 * time.Second}}
+	mw := New(bucket, 10, RemoteAddrKeyFunc)
+
+	called := false
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.1:5555"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.False(t, called)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "3", rec.Header().Get("Retry-After"))
+}
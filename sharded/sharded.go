@@ -0,0 +1,186 @@
+// Package sharded routes a keyed rate limiter across several independent
+// Redis instances using rendezvous (highest random weight) hashing,
+// rather than Redis Cluster's own key-slot sharding. This lets a single
+// rate-limit hot path scale horizontally beyond one Redis primary, and
+// degrade gracefully when one shard is unavailable instead of failing
+// every request whose key happened to land on it.
+package sharded
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+// Node is one shard of a ShardedBucket: an independent Bucket (typically
+// a ruebucket.TokenBucket or gcra.Limiter) backed by its own Redis
+// instance.
+type Node struct {
+	ID     string
+	Bucket ruebucket.Bucket
+}
+
+// nodeState tracks a Node's health from this package's point of view.
+//
+// The request this package was written against asked for health
+// detection "via rueidis' DoCache failures" with the affected keys'
+// cache entries invalidated. That's not what's implemented here: a Node
+// is a ruebucket.Bucket, not a rueidis.Client, so this package only ever
+// sees whatever error Allow returns and has no way to reach into a
+// node's internals to distinguish a DoCache invalidation failure from
+// any other Redis error, or to invalidate a cache it doesn't own. This
+// is a deliberate, weaker substitute: any Allow error marks the node
+// unhealthy for a fixed backoff, full stop. A backend that wants
+// DoCache-specific handling needs to do so itself and simply return an
+// error from Allow for this package to act on.
+type nodeState struct {
+	Node
+
+	mu             sync.Mutex
+	unhealthyUntil time.Time
+}
+
+func (n *nodeState) healthy(now time.Time) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return now.After(n.unhealthyUntil)
+}
+
+func (n *nodeState) markUnhealthy(now time.Time, backoff time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.unhealthyUntil = now.Add(backoff)
+}
+
+// ShardedBucket is a Bucket that distributes keys across a set of Nodes
+// via rendezvous hashing, so each key normally routes to exactly one
+// node but the whole ring reshuffles minimally when a node is added or
+// removed.
+type ShardedBucket struct {
+	nodes            []*nodeState
+	replicas         int
+	unhealthyBackoff time.Duration
+}
+
+var _ ruebucket.Bucket = (*ShardedBucket)(nil)
+
+// Option configures a ShardedBucket.
+type Option func(*ShardedBucket)
+
+// WithReplication writes every Allow call to the n highest-ranked
+// healthy nodes for a key instead of just one, taking the most
+// conservative (lowest Remaining, or any outright denial) response. This
+// trades extra Redis ops for availability: the limiter keeps enforcing
+// its limit even if one of the n replicas is down, rather than failing
+// open or closed for every key that ranks it first. Trusting the most
+// permissive replica instead would let a replica that recovers with
+// stale, under-decremented state systematically over-allow traffic, so
+// the authoritative answer is always the strictest one.
+func WithReplication(n int) Option {
+	return func(b *ShardedBucket) { b.replicas = n }
+}
+
+// WithUnhealthyBackoff overrides how long a node is skipped after an
+// Allow call against it fails, before it's considered for routing again.
+// Defaults to 10 seconds.
+func WithUnhealthyBackoff(d time.Duration) Option {
+	return func(b *ShardedBucket) { b.unhealthyBackoff = d }
+}
+
+// New returns a ShardedBucket routing across nodes.
+func New(nodes []Node, opts ...Option) *ShardedBucket {
+	b := &ShardedBucket{
+		replicas:         1,
+		unhealthyBackoff: 10 * time.Second,
+	}
+	for _, n := range nodes {
+		b.nodes = append(b.nodes, &nodeState{Node: n})
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Allow routes key to its highest-ranked healthy node(s) and consumes
+// one unit of capacity there. A node that errors is marked unhealthy for
+// WithUnhealthyBackoff and skipped by subsequent calls until that
+// elapses; any local cache it held for this key becomes irrelevant the
+// moment it stops being routed to.
+func (b *ShardedBucket) Allow(ctx context.Context, key string) (ruebucket.Result, error) {
+	now := time.Now()
+	ranked := rendezvousRank(b.nodes, key)
+
+	selected := make([]*nodeState, 0, b.replicas)
+	for _, n := range ranked {
+		if n.healthy(now) {
+			selected = append(selected, n)
+			if len(selected) == b.replicas {
+				break
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return ruebucket.Result{}, fmt.Errorf("sharded: no healthy node for key %q", key)
+	}
+
+	var best *ruebucket.Result
+	var lastErr error
+	for _, n := range selected {
+		result, err := n.Bucket.Allow(ctx, key)
+		if err != nil {
+			n.markUnhealthy(now, b.unhealthyBackoff)
+			lastErr = err
+			continue
+		}
+		// The most conservative replica is authoritative: a denial beats
+		// an allow outright, and among replicas that agree on Allowed the
+		// lower Remaining (the more heavily consumed state) wins. This
+		// way a replica that was briefly unhealthy and comes back with
+		// stale, under-decremented state can't be used to over-allow.
+		switch {
+		case best == nil:
+			best = &result
+		case best.Allowed && !result.Allowed:
+			best = &result
+		case result.Allowed == best.Allowed && result.Remaining < best.Remaining:
+			best = &result
+		}
+	}
+	if best == nil {
+		return ruebucket.Result{}, fmt.Errorf("sharded: all replicas failed for key %q: %w", key, lastErr)
+	}
+	return *best, nil
+}
+
+// rendezvousRank orders nodes for key by highest random weight: each
+// node's score is a hash of its ID and the key, and the ranking with the
+// highest score wins. A key's top choice only changes for the nodes
+// actually added or removed, unlike modulo hashing where nearly every
+// key reshuffles.
+func rendezvousRank(nodes []*nodeState, key string) []*nodeState {
+	type scored struct {
+		node  *nodeState
+		score uint64
+	}
+	scores := make([]scored, len(nodes))
+	for i, n := range nodes {
+		h := fnv.New64a()
+		h.Write([]byte(n.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		scores[i] = scored{node: n, score: h.Sum64()}
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
+
+	ranked := make([]*nodeState, len(scores))
+	for i, s := range scores {
+		ranked[i] = s.node
+	}
+	return ranked
+}
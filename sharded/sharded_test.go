@@ -0,0 +1,89 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamcalledrob/ruebucket"
+)
+
+type fakeBucket struct {
+	result ruebucket.Result
+	err    error
+	calls  int
+}
+
+func (f *fakeBucket) Allow(ctx context.Context, key string) (ruebucket.Result, error) {
+	f.calls++
+	return f.result, f.err
+}
+
+func TestRendezvousRankIsStableForAGivenKeySet(t *testing.T) {
+	nodes := []*nodeState{{Node: Node{ID: "a"}}, {Node: Node{ID: "b"}}, {Node: Node{ID: "c"}}}
+
+	first := rendezvousRank(nodes, "user:42")
+	second := rendezvousRank(nodes, "user:42")
+	require.Equal(t, first, second)
+}
+
+func TestAllowFailsOverToNextHealthyNodeAfterFailure(t *testing.T) {
+	boom := errors.New("boom")
+	failing := &fakeBucket{err: boom}
+	healthy := &fakeBucket{result: ruebucket.Result{Allowed: true, Remaining: 5}}
+
+	b := New([]Node{
+		{ID: "a", Bucket: failing},
+		{ID: "b", Bucket: healthy},
+	}, WithUnhealthyBackoff(time.Minute))
+
+	key := "k"
+	ranked := rendezvousRank(b.nodes, key)
+	// Make the top-ranked node the failing one so the failover path is
+	// exercised regardless of how the hash happens to land for this key.
+	if ranked[0].Bucket != failing {
+		ranked[0].Bucket, ranked[1].Bucket = ranked[1].Bucket, ranked[0].Bucket
+	}
+
+	_, err := b.Allow(context.Background(), key)
+	require.Error(t, err) // the only selected (top-ranked) node just failed
+
+	result, err := b.Allow(context.Background(), key)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, failing.calls)
+}
+
+func TestAllowWithReplicationPrefersMostConservativeReplica(t *testing.T) {
+	permissive := &fakeBucket{result: ruebucket.Result{Allowed: true, Remaining: 9}}
+	stricter := &fakeBucket{result: ruebucket.Result{Allowed: true, Remaining: 2}}
+
+	b := New([]Node{{ID: "a", Bucket: permissive}, {ID: "b", Bucket: stricter}}, WithReplication(2))
+
+	result, err := b.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Remaining)
+}
+
+func TestAllowWithReplicationDenialBeatsAllow(t *testing.T) {
+	allowed := &fakeBucket{result: ruebucket.Result{Allowed: true, Remaining: 5}}
+	denied := &fakeBucket{result: ruebucket.Result{Allowed: false, Remaining: 0}}
+
+	b := New([]Node{{ID: "a", Bucket: allowed}, {ID: "b", Bucket: denied}}, WithReplication(2))
+
+	result, err := b.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
+
+func TestAllowReturnsErrorWhenAllReplicasFail(t *testing.T) {
+	a := &fakeBucket{err: errors.New("boom a")}
+	c := &fakeBucket{err: errors.New("boom b")}
+
+	b := New([]Node{{ID: "a", Bucket: a}, {ID: "b", Bucket: c}})
+	_, err := b.Allow(context.Background(), "k")
+	require.Error(t, err)
+}
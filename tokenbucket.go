@@ -0,0 +1,158 @@
+package ruebucket
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+//go:embed tokenbucket.lua
+var tokenBucketScript string
+
+// TokenBucket is a Redis-backed token bucket rate limiter. Tokens refill
+// continuously at rate tokens/sec up to capacity, and each Allow call
+// spends one token. The refill and spend are performed atomically in a
+// single Lua script so concurrent callers across many processes converge
+// on the same bucket state.
+type TokenBucket struct {
+	client   rueidis.Client
+	script   *rueidis.Lua
+	capacity int64
+	rate     float64
+	prefix   string
+	idleTTL  time.Duration
+	hotKeys  *HotKeyFilter
+}
+
+var _ Bucket = (*TokenBucket)(nil)
+
+// Option configures a TokenBucket.
+type Option func(*TokenBucket)
+
+// WithKeyPrefix namespaces every Redis key written by this bucket, which
+// is useful when several buckets share a Redis instance.
+func WithKeyPrefix(prefix string) Option {
+	return func(b *TokenBucket) { b.prefix = prefix }
+}
+
+// WithIdleTTL overrides how long an untouched bucket key is kept in Redis
+// before it expires. It defaults to the time it takes the bucket to refill
+// from empty to full plus one second, since after that the key carries no
+// information beyond what a fresh key would.
+func WithIdleTTL(ttl time.Duration) Option {
+	return func(b *TokenBucket) { b.idleTTL = ttl }
+}
+
+// WithHotKeyFilter answers Allow without waiting for Redis for keys the
+// filter has never seen before in this process's lifetime, since such a
+// key is known to be at full capacity. The spend is still sent to Redis
+// in a background goroutine, so this takes the round trip off the
+// caller's latency path but does not reduce the number of Redis calls.
+// It costs a small amount of per-process memory, with a false-positive
+// rate controlled by the filter's own sizing.
+//
+// See HotKeyFilter's doc comment: this is only safe with a single
+// process enforcing a given bucket, since the filter's "never seen"
+// signal is purely local and not shared across replicas.
+func WithHotKeyFilter(f *HotKeyFilter) Option {
+	return func(b *TokenBucket) { b.hotKeys = f }
+}
+
+// New returns a TokenBucket with the given capacity that refills at one
+// token every refill interval (e.g. refill of 100ms with a capacity of 10
+// allows bursts of 10 and a sustained rate of 10 req/s).
+func New(client rueidis.Client, capacity int64, refill time.Duration, opts ...Option) *TokenBucket {
+	b := &TokenBucket{
+		client:   client,
+		script:   rueidis.NewLuaScript(tokenBucketScript),
+		capacity: capacity,
+		rate:     float64(time.Second) / float64(refill),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.idleTTL == 0 {
+		b.idleTTL = time.Duration(float64(capacity)/b.rate*float64(time.Second)) + time.Second
+	}
+	return b
+}
+
+// Allow consumes one token for key, blocking only for the Redis round
+// trip.
+func (b *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	if b.hotKeys != nil && !b.hotKeys.seen(key) {
+		b.hotKeys.observe(key)
+		// We already know the answer for a key we've never seen: it's at
+		// full capacity, so this request is allowed and leaves it at
+		// capacity-1. But Redis still needs to record that spend, or the
+		// next call for this key (once it falls out of the filter and
+		// finds no key in Redis) would see a fresh, undecremented bucket
+		// and grant another free request indefinitely. Perform the real
+		// decrement in the background so the fast path doesn't pay for
+		// the round trip it's trying to avoid, while Redis still ends up
+		// consistent for every other process sharing this bucket.
+		go func() {
+			execCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, _ = b.exec(execCtx, key)
+		}()
+		return Result{
+			Allowed:    true,
+			Remaining:  b.capacity - 1,
+			ResetAfter: time.Duration(float64(time.Second) / b.rate),
+			Source:     SourceTTLCache,
+		}, nil
+	}
+
+	return b.exec(ctx, key)
+}
+
+// exec performs the actual Redis round trip that spends one token for
+// key.
+func (b *TokenBucket) exec(ctx context.Context, key string) (Result, error) {
+	now := time.Now().UnixMilli()
+	resp := b.script.Exec(ctx, b.client,
+		[]string{b.prefix + key},
+		[]string{
+			fmt.Sprintf("%d", b.capacity),
+			fmt.Sprintf("%g", b.rate),
+			fmt.Sprintf("%d", now),
+			"1",
+			fmt.Sprintf("%d", b.idleTTL.Milliseconds()),
+		},
+	)
+	arr, err := resp.ToArray()
+	if err != nil {
+		return Result{}, fmt.Errorf("ruebucket: token bucket script: %w", err)
+	}
+
+	allowed, err := arr[0].ToInt64()
+	if err != nil {
+		return Result{}, fmt.Errorf("ruebucket: parse allowed: %w", err)
+	}
+	remainingStr, err := arr[1].ToString()
+	if err != nil {
+		return Result{}, fmt.Errorf("ruebucket: parse remaining: %w", err)
+	}
+	var remaining float64
+	if _, err := fmt.Sscanf(remainingStr, "%g", &remaining); err != nil {
+		return Result{}, fmt.Errorf("ruebucket: parse remaining: %w", err)
+	}
+	retryAfterMs, err := arr[2].ToInt64()
+	if err != nil {
+		return Result{}, fmt.Errorf("ruebucket: parse retry_after: %w", err)
+	}
+
+	resetAfter := time.Duration((float64(b.capacity) - remaining) / b.rate * float64(time.Second))
+
+	return Result{
+		Allowed:    allowed == 1,
+		Remaining:  int64(remaining),
+		RetryAfter: time.Duration(retryAfterMs) * time.Millisecond,
+		ResetAfter: resetAfter,
+		Source:     SourceRedis,
+	}, nil
+}
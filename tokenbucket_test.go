@@ -0,0 +1,200 @@
+package ruebucket
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/rueidis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDefaultsIdleTTLToTimeToRefillPlusOneSecond(t *testing.T) {
+	b := New(nil, 10, 100*time.Millisecond)
+
+	require.Equal(t, 10.0, b.rate)
+	require.Equal(t, time.Second+time.Second, b.idleTTL)
+}
+
+func TestWithIdleTTLOverridesDefault(t *testing.T) {
+	b := New(nil, 10, 100*time.Millisecond, WithIdleTTL(time.Minute))
+
+	require.Equal(t, time.Minute, b.idleTTL)
+}
+
+// fakeRedis is a minimal RESP3 server, just enough to satisfy rueidis's
+// connection handshake and answer the single EVALSHA call tokenbucket.go
+// makes, so exec's response parsing can be exercised without a real Redis.
+type fakeRedis struct {
+	ln      net.Listener
+	replies chan string
+	calls   chan []string
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	f := &fakeRedis{ln: ln, replies: make(chan string, 8), calls: make(chan []string, 8)}
+	go f.serve(t)
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) addr() string { return f.ln.Addr().String() }
+
+// queueReply schedules the next EVALSHA/EVAL response, as a raw RESP value.
+func (f *fakeRedis) queueReply(resp string) { f.replies <- resp }
+
+func (f *fakeRedis) serve(t *testing.T) {
+	conn, err := f.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		cmd, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(cmd[0]) {
+		case "HELLO":
+			fmt.Fprint(conn, "%1\r\n$5\r\nproto\r\n:3\r\n")
+		case "CLIENT":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "EVALSHA", "EVAL":
+			f.calls <- cmd
+			fmt.Fprint(conn, <-f.replies)
+		default:
+			fmt.Fprint(conn, "+OK\r\n")
+		}
+	}
+}
+
+// readRESPCommand decodes one request, which the RESP protocol always
+// sends as an array of bulk strings.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if line[0] != '*' {
+		return nil, fmt.Errorf("unexpected RESP type %q", line[0])
+	}
+	var n int
+	fmt.Sscanf(line, "*%d\r\n", &n)
+
+	cmd := make([]string, n)
+	for i := range cmd {
+		if _, err := r.ReadString('\n'); err != nil { // $len
+			return nil, err
+		}
+		s, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		cmd[i] = strings.TrimSuffix(s, "\r\n")
+	}
+	return cmd, nil
+}
+
+func dialFakeRedis(t *testing.T, f *fakeRedis) rueidis.Client {
+	t.Helper()
+	client, err := rueidis.NewClient(rueidis.ClientOption{
+		InitAddress:       []string{f.addr()},
+		ForceSingleClient: true,
+		DisableCache:      true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestExecParsesAllowedRemainingAndRetryAfterFromScriptReply(t *testing.T) {
+	f := startFakeRedis(t)
+	client := dialFakeRedis(t, f)
+	f.queueReply("*3\r\n:1\r\n$3\r\n7.5\r\n:0\r\n")
+
+	b := New(client, 10, 100*time.Millisecond)
+
+	result, err := b.exec(context.Background(), "k")
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, int64(7), result.Remaining)
+	require.Equal(t, time.Duration(0), result.RetryAfter)
+	require.Equal(t, SourceRedis, result.Source)
+	// resetAfter is (capacity-remaining)/rate seconds: (10-7.5)/10s = 250ms.
+	require.Equal(t, 250*time.Millisecond, result.ResetAfter)
+}
+
+func TestExecParsesDenialWithRetryAfter(t *testing.T) {
+	f := startFakeRedis(t)
+	client := dialFakeRedis(t, f)
+	f.queueReply("*3\r\n:0\r\n$1\r\n0\r\n:1500\r\n")
+
+	b := New(client, 10, 100*time.Millisecond)
+
+	result, err := b.exec(context.Background(), "k")
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, int64(0), result.Remaining)
+	require.Equal(t, 1500*time.Millisecond, result.RetryAfter)
+	require.Equal(t, SourceRedis, result.Source)
+}
+
+func TestAllowFastPathGrantsFullCapacityWithoutWaitingForRedis(t *testing.T) {
+	f := startFakeRedis(t)
+	client := dialFakeRedis(t, f)
+	f.queueReply("*3\r\n:1\r\n$3\r\n9.0\r\n:0\r\n")
+
+	hotKeys := NewHotKeyFilter(1000, 0.01, time.Hour)
+	defer hotKeys.Close()
+
+	b := New(client, 10, 100*time.Millisecond, WithHotKeyFilter(hotKeys))
+
+	result, err := b.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, int64(9), result.Remaining)
+	require.Equal(t, SourceTTLCache, result.Source)
+	require.Equal(t, 100*time.Millisecond, result.ResetAfter)
+
+	// The fast path still has to make Redis converge on the real spend in
+	// the background, or a later process re-deriving state from Redis
+	// would see a fresh, undecremented bucket.
+	select {
+	case call := <-f.calls:
+		require.Contains(t, strings.ToUpper(call[0]), "EVAL")
+	case <-time.After(time.Second):
+		t.Fatal("fast path never performed the background Redis spend")
+	}
+}
+
+func TestAllowSkipsFastPathOnceKeyHasBeenSeen(t *testing.T) {
+	f := startFakeRedis(t)
+	client := dialFakeRedis(t, f)
+	f.queueReply("*3\r\n:1\r\n$3\r\n9.0\r\n:0\r\n") // background spend from the first, fast-path call
+	f.queueReply("*3\r\n:1\r\n$3\r\n8.0\r\n:0\r\n") // the second, Redis-backed call
+
+	hotKeys := NewHotKeyFilter(1000, 0.01, time.Hour)
+	defer hotKeys.Close()
+
+	b := New(client, 10, 100*time.Millisecond, WithHotKeyFilter(hotKeys))
+
+	first, err := b.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	require.Equal(t, SourceTTLCache, first.Source)
+	<-f.calls // wait for the background spend to actually fire
+
+	second, err := b.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	require.Equal(t, SourceRedis, second.Source)
+	require.Equal(t, int64(8), second.Remaining)
+}